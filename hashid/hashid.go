@@ -0,0 +1,72 @@
+// Package hashid 把遞增的資料庫流水號轉換成不可預測的公開字串，
+// 避免 API 使用者能單純藉由遞增 id 參數就列舉或刪除其他人的資料。
+package hashid
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/speps/go-hashids"
+)
+
+// 不同資源使用不同的 scope，讓同一個數字 ID 在不同資源類型下產生不同的雜湊值。
+const (
+	TaskID = iota
+	ShareID
+	JobID
+)
+
+// minLength 是產生的雜湊字串最短長度，純粹是為了避免太短的字串看起來可被猜測。
+const minLength = 8
+
+// devSalt 只在 GANTT_HASHID_SALT 未設定時使用，僅適合本機開發，不應用於正式環境。
+const devSalt = "gantt-diagram-dev-salt"
+
+// salt 讀取 GANTT_HASHID_SALT 環境變數作為雜湊鹽值。
+func salt() string {
+	if s := os.Getenv("GANTT_HASHID_SALT"); s != "" {
+		return s
+	}
+	return devSalt
+}
+
+// newHashID 建立綁定特定 scope 的編碼器；scope 會併入 salt，
+// 因此相同的數字 ID 在不同資源類型下會產生不同的雜湊字串。
+func newHashID(scope int) (*hashids.HashID, error) {
+	hd := hashids.NewData()
+	hd.Salt = fmt.Sprintf("%s-%d", salt(), scope)
+	hd.MinLength = minLength
+	return hashids.NewWithData(hd)
+}
+
+// Encode 將數字 ID 依指定 scope 編碼成公開用的雜湊字串；編碼器初始化失敗時回傳空字串。
+func Encode(id int, scope int) string {
+	h, err := newHashID(scope)
+	if err != nil {
+		return ""
+	}
+
+	s, err := h.Encode([]int{id})
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// Decode 將雜湊字串依指定 scope 解回數字 ID；字串格式不符或 scope 不對都會回傳錯誤。
+func Decode(s string, scope int) (int, error) {
+	h, err := newHashID(scope)
+	if err != nil {
+		return 0, err
+	}
+
+	ids, err := h.DecodeWithError(s)
+	if err != nil {
+		return 0, fmt.Errorf("解碼雜湊字串失敗: %w", err)
+	}
+	if len(ids) != 1 {
+		return 0, fmt.Errorf("無效的雜湊字串: %s", s)
+	}
+
+	return ids[0], nil
+}
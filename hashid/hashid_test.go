@@ -0,0 +1,45 @@
+package hashid
+
+import "testing"
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		id    int
+		scope int
+	}{
+		{"task", 3, TaskID},
+		{"share", 3, ShareID},
+		{"job", 42, JobID},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hash := Encode(c.id, c.scope)
+			if hash == "" {
+				t.Fatalf("Encode(%d, %d) 回傳空字串", c.id, c.scope)
+			}
+
+			got, err := Decode(hash, c.scope)
+			if err != nil {
+				t.Fatalf("Decode(%q, %d) 發生錯誤: %v", hash, c.scope, err)
+			}
+			if got != c.id {
+				t.Errorf("Decode(%q, %d) = %d, 預期 %d", hash, c.scope, got, c.id)
+			}
+		})
+	}
+}
+
+func TestDecodeDifferentScopeFails(t *testing.T) {
+	hash := Encode(3, TaskID)
+	if _, err := Decode(hash, ShareID); err == nil {
+		t.Error("預期以不同 scope 解碼應該失敗，卻成功了")
+	}
+}
+
+func TestDecodeMalformedHash(t *testing.T) {
+	if _, err := Decode("not-a-valid-hash", TaskID); err == nil {
+		t.Error("預期解碼格式錯誤的字串應該失敗，卻成功了")
+	}
+}
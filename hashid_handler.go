@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+)
+
+// idContextKey 是 HashID 中介層用來在 request context 中傳遞解碼後數字 ID 的鍵。
+type idContextKey struct{}
+
+// HashID 回傳一個中介層，將請求的 id 參數 (目前取自 query string) 從雜湊字串
+// 解碼回數字 ID，並透過 context 傳給下一個 handler。沒有帶 id 參數時直接放行，
+// 讓 GET/POST 這類不需要 id 的請求不受影響；雜湊格式錯誤則回傳 404，避免洩漏
+// 該 ID 到底存不存在。
+func HashID(scope int) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			raw := r.URL.Query().Get("id")
+			if raw == "" {
+				next(w, r)
+				return
+			}
+
+			id, err := hashid.Decode(raw, scope)
+			if err != nil {
+				http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+				return
+			}
+
+			next(w, r.WithContext(context.WithValue(r.Context(), idContextKey{}, id)))
+		}
+	}
+}
+
+// idFromContext 取出 HashID 中介層解碼後的數字 ID。
+func idFromContext(r *http.Request) (int, bool) {
+	id, ok := r.Context().Value(idContextKey{}).(int)
+	return id, ok
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+)
+
+func TestHashIDMiddleware(t *testing.T) {
+	called := false
+	var gotID int
+	var gotOK bool
+
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		gotID, gotOK = idFromContext(r)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	cases := []struct {
+		name           string
+		query          string
+		wantCalled     bool
+		wantStatusCode int
+		wantID         int
+		wantOK         bool
+	}{
+		{
+			name:           "no id param → skip",
+			query:          "",
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+			wantOK:         false,
+		},
+		{
+			name:           "malformed hash → abort 404",
+			query:          "?id=not-a-valid-hash",
+			wantCalled:     false,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "valid hash → resolved to int",
+			query:          "?id=" + hashid.Encode(3, hashid.TaskID),
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+			wantID:         3,
+			wantOK:         true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			called, gotID, gotOK = false, 0, false
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/tasks"+c.query, nil)
+			rec := httptest.NewRecorder()
+
+			HashID(hashid.TaskID)(inner)(rec, req)
+
+			if called != c.wantCalled {
+				t.Errorf("called = %v, 預期 %v", called, c.wantCalled)
+			}
+			if rec.Code != c.wantStatusCode {
+				t.Errorf("status = %d, 預期 %d", rec.Code, c.wantStatusCode)
+			}
+			if c.wantCalled {
+				if gotOK != c.wantOK {
+					t.Errorf("ok = %v, 預期 %v", gotOK, c.wantOK)
+				}
+				if c.wantOK && gotID != c.wantID {
+					t.Errorf("id = %d, 預期 %d", gotID, c.wantID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package job 提供可持久化、可在背景執行的工作 (Job) 基礎設施：固定大小的
+// Worker Pool 從共用佇列取出 Job 並執行，狀態與結果寫回 JobModel，讓呼叫端可以
+// 輪詢進度，也方便伺服器重啟後把未完成的工作重新排入佇列。
+package job
+
+import (
+	"context"
+	"time"
+)
+
+// Status 代表一個工作在生命週期中的狀態。
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusProcessing
+	StatusComplete
+	StatusError
+	StatusCanceled
+)
+
+// JobModel 是工作紀錄可被持久化的欄位，Props 是建立工作時參數的 JSON 編碼字串。
+type JobModel struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	Props     string    `json:"props"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Result    []byte    `json:"result,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Job 是一個可以交給 Pool 執行的背景工作單元，由呼叫端依 Type 決定具體實作。
+type Job interface {
+	// Do 執行實際工作，應定期檢查 ctx 是否已取消。
+	Do(ctx context.Context)
+	// Type 回傳工作類型，例如 "export_png"。
+	Type() string
+	// Props 回傳建立工作時的參數 (JSON 編碼字串)，供持久化使用。
+	Props() string
+	// SetStatus 更新工作狀態。
+	SetStatus(s Status)
+	// SetError 記錄執行失敗的錯誤並將狀態設為 StatusError。
+	SetError(msg string, err error)
+	// Model 回傳底層的 JobModel，供持久化層讀寫。
+	Model() *JobModel
+}
@@ -0,0 +1,41 @@
+package job
+
+import "context"
+
+// Pool 管理一組固定數量的 Worker，所有 Worker 共用同一個帶緩衝的 Job 佇列。
+type Pool struct {
+	queue   chan Job
+	workers []*Worker
+	cancel  context.CancelFunc
+}
+
+// NewPool 建立一個有 size 個 Worker、佇列緩衝區大小為 queueSize 的 Pool。
+func NewPool(size, queueSize int) *Pool {
+	queue := make(chan Job, queueSize)
+	p := &Pool{queue: queue}
+	for i := 0; i < size; i++ {
+		p.workers = append(p.workers, NewWorker(i, queue))
+	}
+	return p
+}
+
+// Start 啟動所有 Worker 的背景 goroutine；重複呼叫前一個 Pool 必須先 Stop。
+func (p *Pool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for _, w := range p.workers {
+		go w.Run(ctx)
+	}
+}
+
+// Stop 通知所有 Worker 結束，正在執行中的 Job 會被允許跑完當前這一個。
+func (p *Pool) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// Submit 將 Job 放入佇列等待 Worker 執行；佇列已滿時會阻塞呼叫端。
+func (p *Pool) Submit(j Job) {
+	p.queue <- j
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingJob 在 Do 完成時通知一個共用的 WaitGroup，方便測試確認所有工作都被執行過。
+type countingJob struct {
+	model JobModel
+	wg    *sync.WaitGroup
+}
+
+func (c *countingJob) Do(ctx context.Context) {}
+
+func (c *countingJob) Type() string  { return "counting" }
+func (c *countingJob) Props() string { return "{}" }
+
+// SetStatus 只在 process 認定這個 Job 已經跑完（Complete）時才通知 wg，
+// 避免測試在 worker 還在寫入狀態時就讀取 Model().Status。
+func (c *countingJob) SetStatus(s Status) {
+	c.model.Status = s
+	if s == StatusComplete {
+		c.wg.Done()
+	}
+}
+
+func (c *countingJob) SetError(msg string, err error) {
+	c.model.Status = StatusError
+	c.model.Error = msg
+	c.wg.Done()
+}
+
+func (c *countingJob) Model() *JobModel { return &c.model }
+
+func TestPoolRunsAllSubmittedJobs(t *testing.T) {
+	const jobCount = 10
+
+	pool := NewPool(3, jobCount)
+	pool.Start()
+	defer pool.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(jobCount)
+
+	jobs := make([]*countingJob, jobCount)
+	for i := 0; i < jobCount; i++ {
+		jobs[i] = &countingJob{wg: &wg}
+		pool.Submit(jobs[i])
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("並非所有 Job 都在時限內執行完成")
+	}
+
+	for _, j := range jobs {
+		assert.Equal(t, StatusComplete, j.Model().Status)
+	}
+}
@@ -0,0 +1,40 @@
+package job
+
+import "context"
+
+// Worker 不斷從共用佇列取出 Job 並依序執行，直到 context 被取消或佇列被關閉。
+type Worker struct {
+	id    int
+	queue <-chan Job
+}
+
+// NewWorker 建立一個讀取指定佇列的 Worker。
+func NewWorker(id int, queue <-chan Job) *Worker {
+	return &Worker{id: id, queue: queue}
+}
+
+// Run 持續從佇列取出 Job 並執行，直到 ctx 被取消或佇列被關閉才返回。
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case j, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			w.process(ctx, j)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// process 執行單一 Job：先標記為處理中，執行完成後若 Job 本身沒有回報錯誤，
+// 就標記為完成。
+func (w *Worker) process(ctx context.Context, j Job) {
+	j.SetStatus(StatusProcessing)
+	j.Do(ctx)
+
+	if j.Model().Status == StatusProcessing {
+		j.SetStatus(StatusComplete)
+	}
+}
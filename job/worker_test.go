@@ -0,0 +1,91 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJob 是測試用的最小 Job 實作，記錄 Do 被呼叫的次數並回報完成狀態。
+type fakeJob struct {
+	model    JobModel
+	doCalled chan struct{}
+}
+
+func newFakeJob(jobType string) *fakeJob {
+	return &fakeJob{
+		model:    JobModel{Type: jobType, Status: StatusPending},
+		doCalled: make(chan struct{}, 1),
+	}
+}
+
+func (f *fakeJob) Do(ctx context.Context) {
+	f.doCalled <- struct{}{}
+}
+
+func (f *fakeJob) Type() string  { return f.model.Type }
+func (f *fakeJob) Props() string { return "{}" }
+
+func (f *fakeJob) SetStatus(s Status) {
+	f.model.Status = s
+}
+
+func (f *fakeJob) SetError(msg string, err error) {
+	f.model.Status = StatusError
+	f.model.Error = msg
+}
+
+func (f *fakeJob) Model() *JobModel { return &f.model }
+
+func TestWorkerProcessesJobToCompletion(t *testing.T) {
+	queue := make(chan Job, 1)
+	w := NewWorker(0, queue)
+
+	fj := newFakeJob("fake")
+	queue <- fj
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.process(ctx, fj)
+		close(done)
+	}()
+
+	select {
+	case <-fj.doCalled:
+	case <-time.After(time.Second):
+		t.Fatal("Do 沒有在時限內被呼叫")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("process 沒有在時限內返回")
+	}
+
+	assert.Equal(t, StatusComplete, fj.Model().Status)
+}
+
+// erroringJob 在 Do 中自行呼叫 SetError，process 不應該覆寫成 StatusComplete。
+type erroringJob struct {
+	fakeJob
+}
+
+func (e *erroringJob) Do(ctx context.Context) {
+	e.SetError("模擬失敗", assert.AnError)
+}
+
+func TestWorkerKeepsErrorStatus(t *testing.T) {
+	queue := make(chan Job, 1)
+	w := NewWorker(0, queue)
+
+	ej := &erroringJob{fakeJob: *newFakeJob("fake")}
+	w.process(context.Background(), ej)
+
+	assert.Equal(t, StatusError, ej.Model().Status)
+	assert.Equal(t, "模擬失敗", ej.Model().Error)
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+	"github.com/Andrew12082005/GanttDiagram/job"
+)
+
+// jobsFilePath 是 JSONStorage 用來儲存背景工作紀錄的檔案路徑，與 jsonFilePath 並列存放。
+const jobsFilePath = "jobs.json"
+
+// jobPool 是伺服器共用的背景工作執行池，在 main 啟動時建立並啟動。
+var jobPool *job.Pool
+
+// jobPoolSize 與 jobQueueSize 決定 jobPool 的大小；工作量不大，固定值即可。
+const (
+	jobPoolSize  = 4
+	jobQueueSize = 64
+)
+
+// --- 檔案系統實作 (JSONStorage) ---
+
+// loadJobsFromFileLocked 讀取並解析 jobsFilePath，呼叫端必須已持有 dataMutex。
+func loadJobsFromFileLocked() ([]job.JobModel, error) {
+	data, err := os.ReadFile(jobsFilePath)
+	if os.IsNotExist(err) {
+		return []job.JobModel{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("讀取工作紀錄檔案失敗: %w", err)
+	}
+
+	var records []job.JobModel
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析工作紀錄 JSON 失敗: %w", err)
+	}
+	return records, nil
+}
+
+// saveJobsToFileLocked 將工作紀錄列表寫入 jobsFilePath，呼叫端必須已持有 dataMutex。
+func saveJobsToFileLocked(records []job.JobModel) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化工作紀錄 JSON 失敗: %w", err)
+	}
+	if err := os.WriteFile(jobsFilePath, data, 0644); err != nil {
+		return fmt.Errorf("寫入工作紀錄檔案失敗: %w", err)
+	}
+	return nil
+}
+
+// loadJobsFromFile 從 jobsFilePath 讀取工作紀錄列表；檔案不存在時回傳空列表。
+func loadJobsFromFile() ([]job.JobModel, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+	return loadJobsFromFileLocked()
+}
+
+// saveJobsToFile 將工作紀錄列表寫入 jobsFilePath。
+func saveJobsToFile(records []job.JobModel) error {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+	return saveJobsToFileLocked(records)
+}
+
+// --- SQL 實作 (SQLStorage) ---
+
+// loadJobsFromDB 執行 SELECT 查詢，將 jobs 資料表的內容轉換為 JobModel 列表。
+func loadJobsFromDB() ([]job.JobModel, error) {
+	rows, err := db.Query("SELECT id, type, props, status, error, result, created_at, updated_at FROM jobs")
+	if err != nil {
+		return nil, fmt.Errorf("查詢工作紀錄失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var records []job.JobModel
+	for rows.Next() {
+		var m job.JobModel
+		if err := rows.Scan(&m.ID, &m.Type, &m.Props, &m.Status, &m.Error, &m.Result, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("解析工作紀錄資料失敗: %w", err)
+		}
+		records = append(records, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取工作紀錄資料失敗: %w", err)
+	}
+	return records, nil
+}
+
+// saveJobsToDB 在交易中清空 jobs 資料表，接著逐筆寫入新的工作紀錄列表。
+func saveJobsToDB(records []job.JobModel) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM jobs"); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空工作紀錄資料表失敗: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO jobs (id, type, props, status, error, result, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("準備寫入語句失敗: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, m := range records {
+		if _, err := stmt.Exec(m.ID, m.Type, m.Props, m.Status, m.Error, m.Result, m.CreatedAt, m.UpdatedAt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("寫入工作紀錄 %d 失敗: %w", m.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+	return nil
+}
+
+// persistJobToFile 在 dataMutex 保護下完成單一工作紀錄的「讀取 → 更新/新增 → 寫回」，
+// 避免並發的狀態更新互相覆蓋彼此寫入的結果。
+func persistJobToFile(m job.JobModel) error {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	records, err := loadJobsFromFileLocked()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, r := range records {
+		if r.ID == m.ID {
+			records[i] = m
+			found = true
+			break
+		}
+	}
+	if !found {
+		records = append(records, m)
+	}
+
+	return saveJobsToFileLocked(records)
+}
+
+// persistNewJobToFile 在 dataMutex 保護下讀取既有的工作紀錄列表、分配一個
+// 尚未使用的 ID，再連同新工作紀錄一併寫回檔案，避免並發建立時算出相同的 ID。
+func persistNewJobToFile(m job.JobModel) (job.JobModel, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	records, err := loadJobsFromFileLocked()
+	if err != nil {
+		return job.JobModel{}, err
+	}
+
+	m.ID = nextJobID(records)
+
+	if err := saveJobsToFileLocked(append(records, m)); err != nil {
+		return job.JobModel{}, err
+	}
+	return m, nil
+}
+
+// persistJobToDB 以「先刪後插」的方式在單一交易中 upsert 一筆工作紀錄，
+// 交易的隔離性取代了 JSONStorage 需要的 dataMutex。
+func persistJobToDB(m job.JobModel) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM jobs WHERE id = $1", m.ID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("刪除舊工作紀錄失敗: %w", err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO jobs (id, type, props, status, error, result, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		m.ID, m.Type, m.Props, m.Status, m.Error, m.Result, m.CreatedAt, m.UpdatedAt,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("寫入工作紀錄失敗: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+	return nil
+}
+
+// persistNewJobToDB 在單一交易中分配 ID 並插入一筆新的工作紀錄，
+// 交易的隔離性確保並發建立不會算出相同的 ID。
+func persistNewJobToDB(m job.JobModel) (job.JobModel, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return job.JobModel{}, fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	var maxID int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM jobs").Scan(&maxID); err != nil {
+		tx.Rollback()
+		return job.JobModel{}, fmt.Errorf("計算工作紀錄 ID 失敗: %w", err)
+	}
+	m.ID = maxID + 1
+
+	if _, err := tx.Exec(
+		"INSERT INTO jobs (id, type, props, status, error, result, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		m.ID, m.Type, m.Props, m.Status, m.Error, m.Result, m.CreatedAt, m.UpdatedAt,
+	); err != nil {
+		tx.Rollback()
+		return job.JobModel{}, fmt.Errorf("寫入工作紀錄失敗: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return job.JobModel{}, fmt.Errorf("提交交易失敗: %w", err)
+	}
+	return m, nil
+}
+
+// persistJob 將單一工作紀錄的最新狀態原子性地寫回目前生效的 storage；
+// 找不到既有紀錄時新增一筆。
+func persistJob(m job.JobModel) error {
+	return storage.PersistJob(m)
+}
+
+// --- 具體工作型別 ---
+
+// baseJob 提供 job.Job 介面中與持久化有關的共用實作，具體工作型別只需要實作 Do。
+type baseJob struct {
+	model job.JobModel
+}
+
+func (j *baseJob) Type() string         { return j.model.Type }
+func (j *baseJob) Props() string        { return j.model.Props }
+func (j *baseJob) Model() *job.JobModel { return &j.model }
+
+// SetStatus 更新狀態並立即持久化，讓輪詢 API 能看到最新進度。
+func (j *baseJob) SetStatus(s job.Status) {
+	j.model.Status = s
+	j.model.UpdatedAt = time.Now()
+	if err := persistJob(j.model); err != nil {
+		fmt.Printf("Error: 持久化工作 %d 狀態失敗: %v\n", j.model.ID, err)
+	}
+}
+
+// SetError 記錄錯誤、將狀態設為 StatusError 並立即持久化。
+func (j *baseJob) SetError(msg string, err error) {
+	j.model.Status = job.StatusError
+	j.model.Error = fmt.Sprintf("%s: %v", msg, err)
+	j.model.UpdatedAt = time.Now()
+	if err := persistJob(j.model); err != nil {
+		fmt.Printf("Error: 持久化工作 %d 錯誤狀態失敗: %v\n", j.model.ID, err)
+	}
+}
+
+// exportPNGParams 是 export_png 工作的參數。
+type exportPNGParams struct{}
+
+// exportPNGJob 把目前的任務列表渲染成一張簡單的甘特圖 PNG。
+type exportPNGJob struct {
+	baseJob
+}
+
+func newExportPNGJob(model job.JobModel) *exportPNGJob {
+	return &exportPNGJob{baseJob: baseJob{model: model}}
+}
+
+// Do 依任務的 DurationDays 畫出等比例的長條圖，每個任務一列，顏色取自 Task.Color。
+func (j *exportPNGJob) Do(ctx context.Context) {
+	tasks, err := storage.Load()
+	if err != nil {
+		j.SetError("讀取任務數據失敗", err)
+		return
+	}
+
+	const rowHeight = 24
+	const dayWidth = 8
+	const leftMargin = 8
+
+	width := leftMargin + dayWidth*40
+	height := rowHeight*len(tasks) + rowHeight
+	if height < rowHeight {
+		height = rowHeight
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	white := color.RGBA{255, 255, 255, 255}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, white)
+		}
+	}
+
+	for i, t := range tasks {
+		c := parseHexColor(t.Color)
+		y0 := i * rowHeight
+		barWidth := t.DurationDays * dayWidth
+		if barWidth > width-leftMargin {
+			barWidth = width - leftMargin
+		}
+		for x := leftMargin; x < leftMargin+barWidth; x++ {
+			for y := y0 + 4; y < y0+rowHeight-4; y++ {
+				img.Set(x, y, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		j.SetError("編碼 PNG 失敗", err)
+		return
+	}
+
+	j.model.Result = buf.Bytes()
+}
+
+// parseHexColor 將 "#RRGGBB" 轉換成 color.RGBA；格式不符時回傳灰色。
+func parseHexColor(hex string) color.RGBA {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return color.RGBA{128, 128, 128, 255}
+	}
+
+	r, err1 := strconv.ParseUint(hex[0:2], 16, 8)
+	g, err2 := strconv.ParseUint(hex[2:4], 16, 8)
+	b, err3 := strconv.ParseUint(hex[4:6], 16, 8)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return color.RGBA{128, 128, 128, 255}
+	}
+
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+}
+
+// exportICSJob 把目前的任務列表匯出成 RFC 5545 格式的 iCalendar 檔案。
+type exportICSJob struct {
+	baseJob
+}
+
+func newExportICSJob(model job.JobModel) *exportICSJob {
+	return &exportICSJob{baseJob: baseJob{model: model}}
+}
+
+// Do 把每個任務轉換成一個 VEVENT，起訖時間依 Start 與 DurationDays 計算。
+func (j *exportICSJob) Do(ctx context.Context) {
+	tasks, err := storage.Load()
+	if err != nil {
+		j.SetError("讀取任務數據失敗", err)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//GanttDiagram//ExportICS//EN\r\n")
+
+	for _, t := range tasks {
+		start, err := time.Parse("2006-01-02", t.Start)
+		if err != nil {
+			continue
+		}
+		end := start.AddDate(0, 0, t.DurationDays)
+
+		b.WriteString("BEGIN:VEVENT\r\n")
+		fmt.Fprintf(&b, "UID:task-%d@gantt-diagram\r\n", t.ID)
+		fmt.Fprintf(&b, "SUMMARY:%s\r\n", t.Name)
+		fmt.Fprintf(&b, "DTSTART;VALUE=DATE:%s\r\n", start.Format("20060102"))
+		fmt.Fprintf(&b, "DTEND;VALUE=DATE:%s\r\n", end.Format("20060102"))
+		b.WriteString("END:VEVENT\r\n")
+	}
+
+	b.WriteString("END:VCALENDAR\r\n")
+	j.model.Result = []byte(b.String())
+}
+
+// importCSVParams 是 import_csv 工作的參數；CSV 欄位依序為
+// id,name,start,durationDays,color,priority，附帶表頭列。
+type importCSVParams struct {
+	CSV string `json:"csv"`
+}
+
+// importCSVJob 解析 CSV 內容並整批取代目前的任務列表。
+type importCSVJob struct {
+	baseJob
+}
+
+func newImportCSVJob(model job.JobModel) *importCSVJob {
+	return &importCSVJob{baseJob: baseJob{model: model}}
+}
+
+// Do 解析 Props 中的 CSV 字串，轉換成 Task 列表後整批寫入 storage。
+func (j *importCSVJob) Do(ctx context.Context) {
+	var params importCSVParams
+	if err := json.Unmarshal([]byte(j.Props()), &params); err != nil {
+		j.SetError("解析工作參數失敗", err)
+		return
+	}
+
+	reader := csv.NewReader(strings.NewReader(params.CSV))
+	rows, err := reader.ReadAll()
+	if err != nil {
+		j.SetError("解析 CSV 失敗", err)
+		return
+	}
+	if len(rows) > 0 {
+		rows = rows[1:] // 略過表頭列
+	}
+
+	tasks := make([]Task, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 6 {
+			j.SetError("CSV 欄位數量錯誤", fmt.Errorf("預期 6 欄，實際 %d 欄", len(row)))
+			return
+		}
+
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			j.SetError("解析任務 ID 失敗", err)
+			return
+		}
+		duration, err := strconv.Atoi(row[3])
+		if err != nil {
+			j.SetError("解析任務天數失敗", err)
+			return
+		}
+		priority, err := strconv.Atoi(row[5])
+		if err != nil {
+			j.SetError("解析優先級失敗", err)
+			return
+		}
+
+		tasks = append(tasks, Task{
+			ID:           id,
+			Name:         row[1],
+			Start:        row[2],
+			DurationDays: duration,
+			Color:        row[4],
+			Priority:     priority,
+		})
+	}
+
+	if err := storage.Save(tasks); err != nil {
+		j.SetError("寫入任務數據失敗", err)
+		return
+	}
+}
+
+// newJobFromModel 依工作紀錄的 Type 建立對應的具體 Job 實作。
+func newJobFromModel(model job.JobModel) (job.Job, error) {
+	switch model.Type {
+	case "export_png":
+		return newExportPNGJob(model), nil
+	case "export_ics":
+		return newExportICSJob(model), nil
+	case "import_csv":
+		return newImportCSVJob(model), nil
+	default:
+		return nil, fmt.Errorf("不支援的工作類型: %s", model.Type)
+	}
+}
+
+// jobResultContentType 回傳某個工作類型產生結果的 Content-Type。
+func jobResultContentType(jobType string) string {
+	switch jobType {
+	case "export_png":
+		return "image/png"
+	case "export_ics":
+		return "text/calendar; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// --- HTTP 處理函數 ---
+
+// createJobRequest 是 POST /api/jobs 的請求主體。
+type createJobRequest struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// apiJobsHandler 處理 POST /api/jobs：建立一筆工作紀錄、立即持久化為 Pending，
+// 並交給 jobPool 背景執行。
+func apiJobsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req createJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "解析 JSON 失敗"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	model := job.JobModel{
+		Type:      req.Type,
+		Props:     string(req.Params),
+		Status:    job.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	j, err := newJobFromModel(model)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	}
+
+	model, err = storage.PersistNewJob(model)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "儲存工作紀錄失敗: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+	*j.Model() = model
+
+	jobPool.Submit(j)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"hash": hashid.Encode(model.ID, hashid.JobID),
+	})
+}
+
+// nextJobID 回傳目前工作紀錄列表中尚未使用的下一個流水號。
+func nextJobID(records []job.JobModel) int {
+	max := 0
+	for _, r := range records {
+		if r.ID > max {
+			max = r.ID
+		}
+	}
+	return max + 1
+}
+
+// apiJobStatusHandler 處理 GET /api/jobs/{hashid} 與 GET /api/jobs/{hashid}/result。
+func apiJobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	wantResult := strings.HasSuffix(path, "/result")
+	hash := strings.TrimSuffix(path, "/result")
+
+	id, err := hashid.Decode(hash, hashid.JobID)
+	if err != nil {
+		http.Error(w, `{"error": "Job not found"}`, http.StatusNotFound)
+		return
+	}
+
+	records, err := storage.LoadJobs()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "讀取工作紀錄失敗: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	var model *job.JobModel
+	for i := range records {
+		if records[i].ID == id {
+			model = &records[i]
+			break
+		}
+	}
+	if model == nil {
+		http.Error(w, `{"error": "Job not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if !wantResult {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(model)
+		return
+	}
+
+	if model.Status != job.StatusComplete {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "Job result is not ready"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", jobResultContentType(model.Type))
+	w.Write(model.Result)
+}
+
+// requeuePendingJobs 在伺服器啟動時，把重啟前還沒完成的工作重新排入 jobPool。
+func requeuePendingJobs() {
+	records, err := storage.LoadJobs()
+	if err != nil {
+		fmt.Printf("Error: 讀取工作紀錄失敗: %v\n", err)
+		return
+	}
+
+	for _, m := range records {
+		if m.Status != job.StatusPending && m.Status != job.StatusProcessing {
+			continue
+		}
+		m.Status = job.StatusPending
+
+		j, err := newJobFromModel(m)
+		if err != nil {
+			fmt.Printf("Error: 重新排入工作 %d 失敗: %v\n", m.ID, err)
+			continue
+		}
+		jobPool.Submit(j)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Andrew12082005/GanttDiagram/job"
+)
+
+// TestPersistJobConcurrentLostUpdate 確認 persistJob 在並發呼叫下不會遺失更新：
+// 每個呼叫都必須完整地讀取、修改並寫回 jobsFilePath，而不會被其他 goroutine 的
+// 寫入覆蓋。
+func TestPersistJobConcurrentLostUpdate(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+	storage = JSONStorage{}
+
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		m := job.JobModel{ID: i + 1, Type: "export_png", Status: job.StatusPending}
+		go func(m job.JobModel) {
+			defer wg.Done()
+			if err := persistJob(m); err != nil {
+				t.Error(err)
+			}
+		}(m)
+	}
+	wg.Wait()
+
+	records, err := storage.LoadJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != n {
+		t.Errorf("lost updates: wrote %d concurrently, only %d persisted", n, len(records))
+	}
+}
+
+// TestPersistNewJobToFileConcurrent 確認 persistNewJobToFile 在並發呼叫下不會
+// 分配出重複的 ID，也不會遺失任何一次建立。
+func TestPersistNewJobToFileConcurrent(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+	storage = JSONStorage{}
+
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := persistNewJobToFile(job.JobModel{Type: "export_png", Status: job.StatusPending}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records, err := storage.LoadJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != n {
+		t.Fatalf("lost updates: created %d concurrently, only %d persisted", n, len(records))
+	}
+
+	seen := make(map[int]bool, n)
+	for _, r := range records {
+		if seen[r.ID] {
+			t.Fatalf("duplicate job ID %d", r.ID)
+		}
+		seen[r.ID] = true
+	}
+}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"strconv"
 	"sync"
 	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+	"github.com/Andrew12082005/GanttDiagram/job"
 )
 
 // --- 1. 後端數據結構 (與前端任務結構匹配 - 全部小寫) ---
@@ -25,12 +31,96 @@ type Task struct {
 	Priority     int    `json:"priority"`
 }
 
-// 數據檔案路徑
-const jsonFilePath = "gantt.json"
+// taskJSON 是 Task 對外序列化時使用的形狀，ID 改以雜湊字串表示，讓使用者無法
+// 透過遞增 id 猜測或列舉其他任務。只用於 HTTP 回應與凍結分享快照，Task 本身
+// （以及 gantt.json／資料庫的持久化格式）一律維持整數 ID，不受 hashid salt
+// 影響。
+type taskJSON struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Start        string `json:"start"`
+	DurationDays int    `json:"durationDays"`
+	Color        string `json:"color"`
+	Priority     int    `json:"priority"`
+}
+
+// taskInput 是 POST /api/tasks 請求主體裡單一任務的形狀；id 可能是舊資料的
+// 數字、既有任務的雜湊字串，或新任務的空字串，所以先用 json.RawMessage 延後解析。
+type taskInput struct {
+	ID           json.RawMessage `json:"id"`
+	Name         string          `json:"name"`
+	Start        string          `json:"start"`
+	DurationDays int             `json:"durationDays"`
+	Color        string          `json:"color"`
+	Priority     int             `json:"priority"`
+}
+
+// taskToJSON 把 Task 轉換成對外的 taskJSON 形狀，將整數 ID 編碼成雜湊字串。
+func taskToJSON(t Task) taskJSON {
+	return taskJSON{
+		ID:           hashid.Encode(t.ID, hashid.TaskID),
+		Name:         t.Name,
+		Start:        t.Start,
+		DurationDays: t.DurationDays,
+		Color:        t.Color,
+		Priority:     t.Priority,
+	}
+}
+
+// tasksToJSON 對一組 Task 套用 taskToJSON，用於組裝 HTTP 回應。
+func tasksToJSON(tasks []Task) []taskJSON {
+	result := make([]taskJSON, len(tasks))
+	for i, t := range tasks {
+		result[i] = taskToJSON(t)
+	}
+	return result
+}
+
+// taskFromInput 把請求主體的單一任務轉換成 Task。id 可能解碼自雜湊字串、
+// 舊資料留下的數字，或乾脆是空字串；凡是無法辨識的形式一律視為「尚未指派」
+// 並暫時設為 0，交由 apiTasksHandler 在寫入前分配真正的 ID。
+func taskFromInput(aux taskInput) Task {
+	return Task{
+		ID:           parseTaskID(aux.ID),
+		Name:         aux.Name,
+		Start:        aux.Start,
+		DurationDays: aux.DurationDays,
+		Color:        aux.Color,
+		Priority:     aux.Priority,
+	}
+}
+
+// parseTaskID 嘗試把請求主體裡的 id 解析成數字：先當作數字字面量，
+// 再當作雜湊字串；兩者都失敗（或是空字串）就回傳 0。
+func parseTaskID(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+
+	var asNumber int
+	if err := json.Unmarshal(raw, &asNumber); err == nil {
+		return asNumber
+	}
+
+	var asString string
+	if err := json.Unmarshal(raw, &asString); err == nil && asString != "" {
+		if decoded, err := hashid.Decode(asString, hashid.TaskID); err == nil {
+			return decoded
+		}
+	}
+
+	return 0
+}
+
+// 數據檔案路徑；預設值可透過 settingJSONFilePath 設定在不重新編譯的情況下調整。
+var jsonFilePath = "gantt.json"
 
 // Mutex 用於確保對 gantt.json 文件的讀寫是線程安全的。
 var dataMutex sync.Mutex
 
+// storage 是目前生效的任務儲存後端，預設為 JSON 檔案，可透過 -storage 參數切換。
+var storage Storage = JSONStorage{}
+
 // --- 2. 檔案操作與數據加載 ---
 
 // loadTasksFromFile 嘗試從 gantt.json 讀取任務列表。
@@ -91,13 +181,14 @@ func getInitialTasks() []Task {
 		return t.Format("2006-01-02")
 	}
 
-	// P1: #EF4444 (Red), P2: #F97316 (Orange), P3: #FBBF24 (Amber), P4: #3B82F6 (Blue), P5: #10B981 (Green)
+	// 顏色依優先級 (P1~P5) 取自 settingPriorityColor，操作者可透過 /api/settings 調整。
+	// P1: 緊急, P2: 高, P3: 中, P4: 低, P5: 最低
 	return []Task{
-		{ID: 1, Name: "需求收集", Start: formatDate(start), DurationDays: 7, Color: "#3B82F6", Priority: 4},                    // P4 - 低 (藍色)
-		{ID: 2, Name: "系統設計", Start: formatDate(start.AddDate(0, 0, 7)), DurationDays: 5, Color: "#F97316", Priority: 2},   // P2 - 高 (橘色)
-		{ID: 3, Name: "後端開發", Start: formatDate(start.AddDate(0, 0, 12)), DurationDays: 12, Color: "#EF4444", Priority: 1}, // P1 - 緊急 (紅色)
-		{ID: 4, Name: "前端開發", Start: formatDate(start.AddDate(0, 0, 12)), DurationDays: 10, Color: "#FBBF24", Priority: 3}, // P3 - 中 (黃色)
-		{ID: 5, Name: "整合測試", Start: formatDate(start.AddDate(0, 0, 24)), DurationDays: 8, Color: "#10B981", Priority: 5},  // P5 - 最低 (綠色)
+		{ID: 1, Name: "需求收集", Start: formatDate(start), DurationDays: 7, Color: priorityColor(4, "#3B82F6"), Priority: 4},
+		{ID: 2, Name: "系統設計", Start: formatDate(start.AddDate(0, 0, 7)), DurationDays: 5, Color: priorityColor(2, "#F97316"), Priority: 2},
+		{ID: 3, Name: "後端開發", Start: formatDate(start.AddDate(0, 0, 12)), DurationDays: 12, Color: priorityColor(1, "#EF4444"), Priority: 1},
+		{ID: 4, Name: "前端開發", Start: formatDate(start.AddDate(0, 0, 12)), DurationDays: 10, Color: priorityColor(3, "#FBBF24"), Priority: 3},
+		{ID: 5, Name: "整合測試", Start: formatDate(start.AddDate(0, 0, 24)), DurationDays: 8, Color: priorityColor(5, "#10B981"), Priority: 5},
 	}
 }
 
@@ -124,51 +215,51 @@ func apiTasksHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		tasks, err := loadTasksFromFile()
+		tasks, err := storage.Load()
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error": "讀取任務數據失敗: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(tasks)
+		json.NewEncoder(w).Encode(tasksToJSON(tasks))
 
 	case "POST":
-		var tasks []Task
+		var inputs []taskInput
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, `{"error": "讀取請求主體失敗"}`, http.StatusBadRequest)
 			return
 		}
 
-		if err := json.Unmarshal(body, &tasks); err != nil {
+		if err := json.Unmarshal(body, &inputs); err != nil {
 			http.Error(w, `{"error": "解析 JSON 失敗"}`, http.StatusBadRequest)
 			return
 		}
 
-		if err := saveTasksToFile(tasks); err != nil {
+		tasks := make([]Task, len(inputs))
+		for i, in := range inputs {
+			tasks[i] = taskFromInput(in)
+		}
+		assignNewTaskIDs(tasks)
+
+		if err := storage.Save(tasks); err != nil {
 			http.Error(w, fmt.Sprintf(`{"error": "寫入任務數據失敗: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
-		fmt.Printf("Info: 任務數據已成功更新並寫入 %s\n", jsonFilePath)
+		fmt.Printf("Info: 任務數據已成功更新\n")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"message": "任務數據儲存成功"}`))
 
 	case "DELETE":
-		// 處理任務刪除
-		queryID := r.URL.Query().Get("id")
-		if queryID == "" {
+		// 處理任務刪除；id 已由 HashID 中介層從雜湊字串解碼並放進 context。
+		taskID, ok := idFromContext(r)
+		if !ok {
 			http.Error(w, `{"error": "Missing task ID"}`, http.StatusBadRequest)
 			return
 		}
 
-		taskID, err := strconv.Atoi(queryID)
-		if err != nil {
-			http.Error(w, `{"error": "Invalid task ID format"}`, http.StatusBadRequest)
-			return
-		}
-
 		// 1. 載入目前任務
-		currentTasks, err := loadTasksFromFile()
+		currentTasks, err := storage.Load()
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error": "Failed to load tasks for deletion: %v"}`, err), http.StatusInternalServerError)
 			return
@@ -191,12 +282,12 @@ func apiTasksHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// 3. 儲存更新後的列表
-		if err := saveTasksToFile(newTasks); err != nil {
+		if err := storage.Save(newTasks); err != nil {
 			http.Error(w, fmt.Sprintf(`{"error": "Failed to save tasks after deletion: %v"}`, err), http.StatusInternalServerError)
 			return
 		}
 
-		fmt.Printf("Info: Task ID %d deleted and data saved to %s\n", taskID, jsonFilePath)
+		fmt.Printf("Info: Task ID %d deleted\n", taskID)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(fmt.Sprintf(`{"message": "Task ID %d deleted successfully"}`, taskID)))
 
@@ -206,20 +297,69 @@ func apiTasksHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// assignNewTaskIDs 為 ID 尚未指派（UnmarshalJSON 解碼失敗或為空時設為 0）的任務
+// 分配一個在這批任務中唯一的新 ID，讓新建立的任務也能正常寫入。
+func assignNewTaskIDs(tasks []Task) {
+	maxID := 0
+	for _, t := range tasks {
+		if t.ID > maxID {
+			maxID = t.ID
+		}
+	}
+	for i := range tasks {
+		if tasks[i].ID == 0 {
+			maxID++
+			tasks[i].ID = maxID
+		}
+	}
+}
+
 // --- 4. 主函數 (啟動伺服器) ---
 
 func main() {
+	storageKind := flag.String("storage", "json", "任務儲存後端: json, sqlite 或 postgres")
+	dsn := flag.String("dsn", "", "sqlite/postgres 的資料來源名稱 (DSN)，storage 不為 json 時必填")
+	flag.Parse()
+
+	s, err := newStorage(*storageKind, *dsn)
+	if err != nil {
+		fmt.Printf("Error: 初始化 storage 失敗: %v\n", err)
+		return
+	}
+	storage = s
+
+	if err := ensureDefaultSettings(); err != nil {
+		fmt.Printf("Error: 初始化預設設定失敗: %v\n", err)
+	}
+	settings := GetSettingByNames(settingPort, settingJSONFilePath)
+	port := settings[settingPort]
+	if port == "" {
+		port = "8080"
+	}
+	if path := settings[settingJSONFilePath]; path != "" {
+		jsonFilePath = path
+	}
+
+	jobPool = job.NewPool(jobPoolSize, jobQueueSize)
+	jobPool.Start()
+	requeuePendingJobs()
+
 	// 設置路由
 	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/api/tasks", apiTasksHandler)
+	http.HandleFunc("/api/tasks", HashID(hashid.TaskID)(apiTasksHandler))
+	http.HandleFunc("/api/shares", apiSharesHandler)
+	http.HandleFunc("/api/shares/", apiShareDeleteHandler)
+	http.HandleFunc("/s/", publicShareHandler)
+	http.HandleFunc("/api/jobs", apiJobsHandler)
+	http.HandleFunc("/api/jobs/", apiJobStatusHandler)
+	http.HandleFunc("/api/settings", apiSettingsHandler)
 
-	port := "8080"
 	fmt.Printf("Go 甘特圖後端已啟動，請在瀏覽器中開啟 http://localhost:%s\n", port)
 
-	// 首次嘗試載入數據，確保 gantt.json 存在或被創建
+	// 首次嘗試載入數據，確保初始資料存在或被創建
 	// 注意：這裡移除了原有的 defer unlock 邏輯以避免死鎖
-	if _, err := loadTasksFromFile(); err != nil {
-		fmt.Printf("Error: 初始載入/創建檔案失敗: %v\n", err)
+	if _, err := storage.Load(); err != nil {
+		fmt.Printf("Error: 初始載入/創建資料失敗: %v\n", err)
 	}
 
 	// 啟動伺服器
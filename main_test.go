@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
 )
 
 // 安裝: go get github.com/DATA-DOG/go-sqlmock
@@ -134,12 +141,128 @@ func TestLoadTasksFromDB_QueryError(t *testing.T) {
 		WillReturnError(fmt.Errorf("模擬查詢錯誤"))
 
 	tasks, err := loadTasksFromDB()
-	
+
 	// 期望返回錯誤
 	assert.Error(t, err, "loadTasksFromDB 應返回錯誤")
 	assert.Nil(t, tasks, "tasks 應為 nil")
-	
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("未滿足的 Mock 期望: %s", err)
 	}
+}
+
+// --- 測試 apiTasksHandler POST ---
+
+// TestApiTasksHandlerPost_NewTask 確認新建立的任務（帶著空字串 ID）能透過
+// POST /api/tasks 正常寫入，並被伺服器分配一個真正的 ID。
+func TestApiTasksHandlerPost_NewTask(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+	storage = JSONStorage{}
+
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	existingID := 101
+	body := fmt.Sprintf(
+		`[{"id":%q,"name":"既有任務","start":"2026-02-01","durationDays":3,"color":"#AAAAAA","priority":5},
+		  {"id":"","name":"新任務","start":"2026-02-04","durationDays":2,"color":"#BBBBBB","priority":1}]`,
+		hashid.Encode(existingID, hashid.TaskID),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks", bytes.NewReader([]byte(body)))
+	rec := httptest.NewRecorder()
+
+	apiTasksHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("狀態碼 = %d, 預期 %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	saved, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("儲存的任務數 = %d, 預期 2", len(saved))
+	}
+	if saved[0].ID != existingID {
+		t.Errorf("既有任務的 ID = %d, 預期 %d", saved[0].ID, existingID)
+	}
+	if saved[1].ID == 0 || saved[1].ID == existingID {
+		t.Errorf("新任務應被分配一個非零且不重複的 ID, 得到 %d", saved[1].ID)
+	}
+
+}
+
+// TestLoadTasksFromFile_LegacyNumericIDs 確認 gantt.json 裡舊資料的整數 id
+// （雜湊化之前就寫入的格式）仍然可以被正常讀取，而不會因為 Task 的 JSON 形狀
+// 變動而解析失敗。
+func TestLoadTasksFromFile_LegacyNumericIDs(t *testing.T) {
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	legacyJSON := `[{"id":1,"name":"舊任務","start":"2026-02-01","durationDays":3,"color":"#AAAAAA","priority":5}]`
+	if err := os.WriteFile(jsonFilePath, []byte(legacyJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := loadTasksFromFile()
+	if err != nil {
+		t.Fatalf("loadTasksFromFile 不應該在讀取舊格式資料時失敗: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != 1 {
+		t.Fatalf("got %+v", tasks)
+	}
+}
+
+// TestApiTasksHandlerGet_EncodesHashedIDs 確認 GET /api/tasks 回應裡的 id
+// 是雜湊字串，而不是內部使用的整數 ID（持久化格式本身不受影響）。
+func TestApiTasksHandlerGet_EncodesHashedIDs(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+	storage = JSONStorage{}
+
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	legacyJSON := `[{"id":1,"name":"舊任務","start":"2026-02-01","durationDays":3,"color":"#AAAAAA","priority":5}]`
+	if err := os.WriteFile(jsonFilePath, []byte(legacyJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	rec := httptest.NewRecorder()
+	apiTasksHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("狀態碼 = %d, 預期 %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var got []taskJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	assert.Equal(t, hashid.Encode(1, hashid.TaskID), got[0].ID)
 }
\ No newline at end of file
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// settingsFilePath 是 JSONStorage 用來儲存設定的檔案路徑，與 jsonFilePath 並列存放。
+const settingsFilePath = "settings.json"
+
+// Setting 是一筆可由操作者在不重新編譯的情況下調整的設定值。
+// Type 目前只是給前端顯示用的提示 (例如 "string"、"int")，後端一律以字串儲存。
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// 下列是目前已知的設定名稱，取代原本寫死在程式碼裡的常數。
+const (
+	settingPort          = "port"
+	settingJSONFilePath  = "json_file_path"
+	settingPriorityColor = "priority_color_" // 後面接 1~5，對應 Task.Priority
+)
+
+// defaultSettings 是伺服器第一次啟動、settings 尚未有任何資料時要灌入的預設值，
+// 數值沿用原本寫死在 main.go / getInitialTasks 裡的版本。
+func defaultSettings() []Setting {
+	return []Setting{
+		{Name: settingPort, Value: "8080", Type: "int"},
+		{Name: settingJSONFilePath, Value: "gantt.json", Type: "string"},
+		{Name: settingPriorityColor + "1", Value: "#EF4444", Type: "color"},
+		{Name: settingPriorityColor + "2", Value: "#F97316", Type: "color"},
+		{Name: settingPriorityColor + "3", Value: "#FBBF24", Type: "color"},
+		{Name: settingPriorityColor + "4", Value: "#3B82F6", Type: "color"},
+		{Name: settingPriorityColor + "5", Value: "#10B981", Type: "color"},
+	}
+}
+
+// ensureDefaultSettings 在啟動時確保 defaultSettings 裡的每一筆設定都已存在，
+// 不會覆蓋操作者已經調整過的值。
+func ensureDefaultSettings() error {
+	existing, err := storage.ListSettings()
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, s := range existing {
+		have[s.Name] = true
+	}
+
+	for _, s := range defaultSettings() {
+		if have[s.Name] {
+			continue
+		}
+		if err := storage.SaveSetting(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- 快取層 ---
+
+// settingCachePrefix 是 sync.Map 裡設定值鍵的前綴，避免與其他用途混用同一個 Map 時撞名。
+const settingCachePrefix = "setting_"
+
+// settingCache 快取曾經讀過的設定值；GetSettingByNames 是唯一的讀取入口。
+var settingCache sync.Map
+
+// GetSettingByNames 讀取一組設定值：先查快取，只有快取沒命中的名稱才會真的查詢
+// storage；如果所有名稱都已經在快取中，完全不會觸發任何查詢——這正是這裡要修正的
+// 問題：對空的 miss 列表仍然送出 `WHERE name IN ()` 查詢。
+func GetSettingByNames(names ...string) map[string]string {
+	result := make(map[string]string, len(names))
+	miss := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if v, ok := settingCache.Load(settingCachePrefix + name); ok {
+			result[name] = v.(string)
+		} else {
+			miss = append(miss, name)
+		}
+	}
+
+	if len(miss) == 0 {
+		return result
+	}
+
+	fetched, err := storage.GetSettingsByNames(miss)
+	if err != nil {
+		fmt.Printf("Error: 讀取設定失敗: %v\n", err)
+		return result
+	}
+
+	for name, value := range fetched {
+		settingCache.Store(settingCachePrefix+name, value)
+		result[name] = value
+	}
+
+	return result
+}
+
+// populateSettingCache 直接把寫入的設定值灌進快取，讓下一次 GetSettingByNames
+// 命中快取而不必重新查詢 storage。
+func populateSettingCache(name, value string) {
+	settingCache.Store(settingCachePrefix+name, value)
+}
+
+// priorityColor 回傳指定優先級 (1~5) 目前設定的顏色，設定不存在時回傳 fallback。
+func priorityColor(priority int, fallback string) string {
+	name := fmt.Sprintf("%s%d", settingPriorityColor, priority)
+	if v, ok := GetSettingByNames(name)[name]; ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// --- 檔案系統實作 (JSONStorage) ---
+
+// loadSettingsFromFile 從 settingsFilePath 讀取設定列表；檔案不存在時回傳空列表。
+func loadSettingsFromFile() ([]Setting, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	data, err := os.ReadFile(settingsFilePath)
+	if os.IsNotExist(err) {
+		return []Setting{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("讀取設定檔案失敗: %w", err)
+	}
+
+	var settings []Setting
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("解析設定 JSON 失敗: %w", err)
+	}
+	return settings, nil
+}
+
+// saveSettingsToFile 將設定列表寫入 settingsFilePath。
+func saveSettingsToFile(settings []Setting) error {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化設定 JSON 失敗: %w", err)
+	}
+	if err := os.WriteFile(settingsFilePath, data, 0644); err != nil {
+		return fmt.Errorf("寫入設定檔案失敗: %w", err)
+	}
+	return nil
+}
+
+// --- SQL 實作 (SQLStorage) ---
+
+// listSettingsFromDB 讀取 settings 資料表的所有設定。
+func listSettingsFromDB() ([]Setting, error) {
+	rows, err := db.Query("SELECT name, value, type FROM settings")
+	if err != nil {
+		return nil, fmt.Errorf("查詢設定失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []Setting
+	for rows.Next() {
+		var s Setting
+		if err := rows.Scan(&s.Name, &s.Value, &s.Type); err != nil {
+			return nil, fmt.Errorf("解析設定資料失敗: %w", err)
+		}
+		settings = append(settings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取設定資料失敗: %w", err)
+	}
+	return settings, nil
+}
+
+// saveSettingToDB 以「先刪後插」的方式 upsert 一筆設定。
+func saveSettingToDB(s Setting) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM settings WHERE name = $1", s.Name); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("刪除舊設定失敗: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO settings (name, value, type) VALUES ($1, $2, $3)", s.Name, s.Value, s.Type); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("寫入設定失敗: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+	return nil
+}
+
+// getSettingsByNamesFromDB 只在 names 非空時才對 settings 資料表發出
+// `WHERE name IN (...)` 查詢；names 為空時直接回傳空結果。
+func getSettingsByNamesFromDB(names []string) (map[string]string, error) {
+	result := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(names))
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = name
+	}
+
+	query := fmt.Sprintf("SELECT name, value FROM settings WHERE name IN (%s)", strings.Join(placeholders, ", "))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查詢設定失敗: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("解析設定資料失敗: %w", err)
+		}
+		result[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取設定資料失敗: %w", err)
+	}
+
+	return result, nil
+}
+
+// --- HTTP 處理函數 ---
+
+// apiSettingsHandler 處理 GET (列出所有設定) 與 POST (新增/更新設定並灌入快取)。
+func apiSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		settings, err := storage.ListSettings()
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "讀取設定失敗: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(settings)
+
+	case "POST":
+		var settings []Setting
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, `{"error": "解析 JSON 失敗"}`, http.StatusBadRequest)
+			return
+		}
+
+		for _, s := range settings {
+			if err := storage.SaveSetting(s); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": "儲存設定失敗: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			populateSettingCache(s.Name, s.Value)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "設定已更新"}`))
+
+	default:
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+	}
+}
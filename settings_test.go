@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- 測試 getSettingsByNamesFromDB 的「空 miss 列表不查詢」保護 ---
+
+func TestGetSettingsByNamesFromDB_EmptyNamesSkipsQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	// 刻意不設定任何 ExpectQuery：如果 getSettingsByNamesFromDB 在 names 為空時
+	// 仍然對 db 發出查詢，sqlmock 會回傳「未預期的呼叫」錯誤，下面的 assert.NoError
+	// 就會失敗，藉此驗證這個關鍵保護確實生效。
+	result, err := getSettingsByNamesFromDB(nil)
+
+	assert.NoError(t, err, "names 為空時不應該查詢資料庫")
+	assert.Empty(t, result)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+func TestGetSettingsByNamesFromDB_QueriesWhenNamesGiven(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	rows := sqlmock.NewRows([]string{"name", "value"}).
+		AddRow(settingPort, "9090")
+
+	mock.ExpectQuery("SELECT name, value FROM settings WHERE name IN \\(\\$1\\)").
+		WithArgs(settingPort).
+		WillReturnRows(rows)
+
+	result, err := getSettingsByNamesFromDB([]string{settingPort})
+
+	assert.NoError(t, err, "getSettingsByNamesFromDB 不應返回錯誤")
+	assert.Equal(t, "9090", result[settingPort])
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+// --- 測試 GetSettingByNames 快取層：全部命中快取時不應呼叫 storage ---
+
+// spySettingsStorage 只用來記錄 GetSettingsByNames 被呼叫的次數。
+type spySettingsStorage struct {
+	Storage
+	calls int
+}
+
+func (s *spySettingsStorage) GetSettingsByNames(names []string) (map[string]string, error) {
+	s.calls++
+	return map[string]string{}, nil
+}
+
+func TestGetSettingByNames_CacheHitSkipsStorage(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+
+	const key = "test_cache_hit_setting"
+	settingCache.Store(settingCachePrefix+key, "cached-value")
+	defer settingCache.Delete(settingCachePrefix + key)
+
+	spy := &spySettingsStorage{}
+	storage = spy
+
+	result := GetSettingByNames(key)
+
+	assert.Equal(t, "cached-value", result[key])
+	assert.Equal(t, 0, spy.calls, "全部命中快取時不應該呼叫 storage.GetSettingsByNames")
+}
+
+// --- 測試 apiSettingsHandler POST 會把寫入的值灌進快取 ---
+
+// noopSaveStorage 讓 SaveSetting 直接成功，不做任何實際的 I/O。
+type noopSaveStorage struct {
+	Storage
+}
+
+func (noopSaveStorage) SaveSetting(s Setting) error { return nil }
+
+func TestApiSettingsHandlerPost_PopulatesCache(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+	storage = noopSaveStorage{}
+
+	const key = "test_populate_on_write_setting"
+	settingCache.Delete(settingCachePrefix + key)
+	defer settingCache.Delete(settingCachePrefix + key)
+
+	body := `[{"name":"` + key + `","value":"新的值","type":"string"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/settings", bytes.NewReader([]byte(body)))
+	rec := httptest.NewRecorder()
+
+	apiSettingsHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("狀態碼 = %d, 預期 %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	spy := &spySettingsStorage{}
+	storage = spy
+
+	result := GetSettingByNames(key)
+
+	assert.Equal(t, "新的值", result[key])
+	assert.Equal(t, 0, spy.calls, "寫入後的值應該已經在快取中，不應該再查詢 storage")
+}
+
+func TestGetSettingByNames_CacheMissCallsStorage(t *testing.T) {
+	oldStorage := storage
+	defer func() { storage = oldStorage }()
+
+	const key = "test_cache_miss_setting"
+	settingCache.Delete(settingCachePrefix + key)
+	defer settingCache.Delete(settingCachePrefix + key)
+
+	spy := &spySettingsStorage{}
+	storage = spy
+
+	GetSettingByNames(key)
+
+	assert.Equal(t, 1, spy.calls, "快取沒命中時應該呼叫一次 storage.GetSettingsByNames")
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+)
+
+// sharesFilePath 是 JSONStorage 用來儲存分享連結的檔案路徑，與 jsonFilePath 並列存放。
+const sharesFilePath = "shares.json"
+
+// 這些是 ConsumeShare 在分享連結無法存取時回傳的標準錯誤，讓呼叫端可以用
+// errors.Is 判斷該回應哪一種 HTTP 狀態碼。
+var (
+	ErrShareNotFound      = errors.New("share not found")
+	ErrShareUnavailable   = errors.New("share has expired or is no longer available")
+	ErrShareWrongPassword = errors.New("incorrect password")
+)
+
+// Share 代表一個公開、唯讀的甘特圖分享連結：建立當下的任務列表會被凍結在
+// TasksJSON 裡，之後任務異動不會影響已經發出去的連結。
+type Share struct {
+	ID          int        `json:"id"`
+	Hash        string     `json:"hash"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpireAt    *time.Time `json:"expireAt,omitempty"`
+	RemainViews *int       `json:"remainViews,omitempty"`
+	Password    string     `json:"password,omitempty"`
+	TasksJSON   []byte     `json:"tasksJson"`
+}
+
+// IsAvailable 判斷分享連結是否還能被存取：觀看次數用完或已過期都視為失效，
+// 對應外部 Cloudreve Share 的 RemainDownloads == 0 / 過期檢查邏輯。
+func (s Share) IsAvailable() bool {
+	if s.RemainViews != nil && *s.RemainViews <= 0 {
+		return false
+	}
+	if s.ExpireAt != nil && time.Now().After(*s.ExpireAt) {
+		return false
+	}
+	return true
+}
+
+// indexOfShare 回傳指定 ID 的分享連結在列表中的位置，找不到時回傳 -1。
+func indexOfShare(shares []Share, id int) int {
+	for i, s := range shares {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// shareSummary 是分享連結對外列表時的精簡表示，不包含密碼與凍結的任務內容。
+type shareSummary struct {
+	Hash        string     `json:"hash"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpireAt    *time.Time `json:"expireAt,omitempty"`
+	RemainViews *int       `json:"remainViews,omitempty"`
+	HasPassword bool       `json:"hasPassword"`
+}
+
+// --- 檔案系統實作 (JSONStorage) ---
+
+// loadSharesFromFileLocked 讀取並解析 sharesFilePath，呼叫端必須已持有 dataMutex。
+func loadSharesFromFileLocked() ([]Share, error) {
+	data, err := os.ReadFile(sharesFilePath)
+	if os.IsNotExist(err) {
+		return []Share{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("讀取分享連結檔案失敗: %w", err)
+	}
+
+	var shares []Share
+	if err := json.Unmarshal(data, &shares); err != nil {
+		return nil, fmt.Errorf("解析分享連結 JSON 失敗: %w", err)
+	}
+	return shares, nil
+}
+
+// saveSharesToFileLocked 將分享連結列表寫入 sharesFilePath，呼叫端必須已持有 dataMutex。
+func saveSharesToFileLocked(shares []Share) error {
+	data, err := json.MarshalIndent(shares, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化分享連結 JSON 失敗: %w", err)
+	}
+	if err := os.WriteFile(sharesFilePath, data, 0644); err != nil {
+		return fmt.Errorf("寫入分享連結檔案失敗: %w", err)
+	}
+	return nil
+}
+
+// loadSharesFromFile 從 sharesFilePath 讀取分享連結列表；檔案不存在時回傳空列表。
+func loadSharesFromFile() ([]Share, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+	return loadSharesFromFileLocked()
+}
+
+// saveSharesToFile 將分享連結列表寫入 sharesFilePath。
+func saveSharesToFile(shares []Share) error {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+	return saveSharesToFileLocked(shares)
+}
+
+// persistNewShareToFile 在 dataMutex 保護下讀取既有的分享連結列表、分配一個
+// 尚未使用的 ID 與 Hash，再連同新分享連結一併寫回檔案，避免並發建立時因為
+// LoadShares 之後才 SaveShares 而互相覆蓋彼此的寫入。
+func persistNewShareToFile(s Share) (Share, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	shares, err := loadSharesFromFileLocked()
+	if err != nil {
+		return Share{}, err
+	}
+
+	s.ID = nextShareID(shares)
+	s.Hash = hashid.Encode(s.ID, hashid.ShareID)
+
+	if err := saveSharesToFileLocked(append(shares, s)); err != nil {
+		return Share{}, err
+	}
+	return s, nil
+}
+
+// removeShareFromFile 在 dataMutex 保護下讀取既有的分享連結列表、移除指定 ID
+// 的分享連結再寫回檔案，找不到時回傳 ErrShareNotFound。
+func removeShareFromFile(id int) error {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	shares, err := loadSharesFromFileLocked()
+	if err != nil {
+		return err
+	}
+
+	index := indexOfShare(shares, id)
+	if index == -1 {
+		return ErrShareNotFound
+	}
+	newShares := append(shares[:index], shares[index+1:]...)
+
+	return saveSharesToFileLocked(newShares)
+}
+
+// --- SQL 實作 (SQLStorage) ---
+
+// loadSharesFromDB 執行 SELECT 查詢，將 shares 資料表的內容轉換為 Share 列表。
+func loadSharesFromDB() ([]Share, error) {
+	rows, err := db.Query("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares")
+	if err != nil {
+		return nil, fmt.Errorf("查詢分享連結失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []Share
+	for rows.Next() {
+		s, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析分享連結資料失敗: %w", err)
+		}
+		shares = append(shares, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取分享連結資料失敗: %w", err)
+	}
+
+	return shares, nil
+}
+
+// rowScanner 是 *sql.Row 與 *sql.Rows 共同實作的介面，讓 scanShare 可以重複使用。
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanShare 將一列 shares 資料表的結果掃描成 Share，並處理可為 NULL 的欄位。
+func scanShare(row rowScanner) (Share, error) {
+	var (
+		s           Share
+		expireAt    sql.NullTime
+		remainViews sql.NullInt64
+	)
+
+	if err := row.Scan(&s.ID, &s.Hash, &s.CreatedAt, &expireAt, &remainViews, &s.Password, &s.TasksJSON); err != nil {
+		return Share{}, err
+	}
+
+	if expireAt.Valid {
+		s.ExpireAt = &expireAt.Time
+	}
+	if remainViews.Valid {
+		v := int(remainViews.Int64)
+		s.RemainViews = &v
+	}
+
+	return s, nil
+}
+
+// saveSharesToDB 在交易中清空 shares 資料表，接著逐筆寫入新的分享連結列表。
+func saveSharesToDB(shares []Share) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM shares"); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空分享連結資料表失敗: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO shares (id, hash, created_at, expire_at, remain_views, password, tasks_json) VALUES ($1, $2, $3, $4, $5, $6, $7)")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("準備寫入語句失敗: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range shares {
+		if _, err := stmt.Exec(s.ID, s.Hash, s.CreatedAt, s.ExpireAt, s.RemainViews, s.Password, s.TasksJSON); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("寫入分享連結 %d 失敗: %w", s.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+
+	return nil
+}
+
+// consumeShareFromDB 在單一交易中讀取、驗證並遞減指定分享連結的 RemainViews；
+// 交易的隔離性確保並發請求不會重複消耗同一個觀看名額。
+func consumeShareFromDB(id int, password string) (Share, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Share{}, fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	row := tx.QueryRow("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares WHERE id = $1", id)
+	share, err := scanShare(row)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return Share{}, ErrShareNotFound
+		}
+		return Share{}, fmt.Errorf("查詢分享連結失敗: %w", err)
+	}
+
+	if !share.IsAvailable() {
+		tx.Rollback()
+		return Share{}, ErrShareUnavailable
+	}
+	if share.Password != "" && share.Password != password {
+		tx.Rollback()
+		return Share{}, ErrShareWrongPassword
+	}
+
+	if share.RemainViews != nil {
+		remaining := *share.RemainViews - 1
+		if _, err := tx.Exec("UPDATE shares SET remain_views = $1 WHERE id = $2", remaining, id); err != nil {
+			tx.Rollback()
+			return Share{}, fmt.Errorf("更新觀看次數失敗: %w", err)
+		}
+		share.RemainViews = &remaining
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Share{}, fmt.Errorf("提交交易失敗: %w", err)
+	}
+
+	return share, nil
+}
+
+// persistNewShareToDB 在單一交易中分配 ID、計算 Hash 並插入一筆新的分享連結，
+// 交易的隔離性確保並發建立不會算出相同的 ID。
+func persistNewShareToDB(s Share) (Share, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Share{}, fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	var maxID int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM shares").Scan(&maxID); err != nil {
+		tx.Rollback()
+		return Share{}, fmt.Errorf("計算分享連結 ID 失敗: %w", err)
+	}
+	s.ID = maxID + 1
+	s.Hash = hashid.Encode(s.ID, hashid.ShareID)
+
+	if _, err := tx.Exec("INSERT INTO shares (id, hash, created_at, expire_at, remain_views, password, tasks_json) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		s.ID, s.Hash, s.CreatedAt, s.ExpireAt, s.RemainViews, s.Password, s.TasksJSON); err != nil {
+		tx.Rollback()
+		return Share{}, fmt.Errorf("寫入分享連結失敗: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Share{}, fmt.Errorf("提交交易失敗: %w", err)
+	}
+
+	return s, nil
+}
+
+// removeShareFromDB 在單一交易中刪除指定 ID 的分享連結，找不到時回傳 ErrShareNotFound。
+func removeShareFromDB(id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	res, err := tx.Exec("DELETE FROM shares WHERE id = $1", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("刪除分享連結失敗: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("確認刪除結果失敗: %w", err)
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrShareNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+	return nil
+}
+
+// --- HTTP 處理函數 ---
+
+// createShareRequest 是 POST /api/shares 的請求主體。
+type createShareRequest struct {
+	ExpireSeconds int    `json:"expireSeconds"`
+	MaxViews      int    `json:"maxViews"`
+	Password      string `json:"password"`
+}
+
+// apiSharesHandler 處理 POST (建立分享連結) 與 GET (列出分享連結)。
+func apiSharesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "POST":
+		var req createShareRequest
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, `{"error": "讀取請求主體失敗"}`, http.StatusBadRequest)
+			return
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, `{"error": "解析 JSON 失敗"}`, http.StatusBadRequest)
+				return
+			}
+		}
+
+		tasks, err := storage.Load()
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "讀取任務數據失敗: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		tasksJSON, err := json.Marshal(tasksToJSON(tasks))
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "凍結任務數據失敗: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		share := Share{
+			CreatedAt: time.Now(),
+			Password:  req.Password,
+			TasksJSON: tasksJSON,
+		}
+		if req.ExpireSeconds > 0 {
+			expireAt := share.CreatedAt.Add(time.Duration(req.ExpireSeconds) * time.Second)
+			share.ExpireAt = &expireAt
+		}
+		if req.MaxViews > 0 {
+			share.RemainViews = &req.MaxViews
+		}
+
+		share, err = storage.PersistShare(share)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "儲存分享連結失敗: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"hash": share.Hash,
+			"url":  "/s/" + share.Hash,
+		})
+
+	case "GET":
+		shares, err := storage.LoadShares()
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "讀取分享連結失敗: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		summaries := make([]shareSummary, 0, len(shares))
+		for _, s := range shares {
+			summaries = append(summaries, shareSummary{
+				Hash:        s.Hash,
+				CreatedAt:   s.CreatedAt,
+				ExpireAt:    s.ExpireAt,
+				RemainViews: s.RemainViews,
+				HasPassword: s.Password != "",
+			})
+		}
+		json.NewEncoder(w).Encode(summaries)
+
+	default:
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// nextShareID 回傳目前分享連結列表中尚未使用的下一個流水號。
+func nextShareID(shares []Share) int {
+	max := 0
+	for _, s := range shares {
+		if s.ID > max {
+			max = s.ID
+		}
+	}
+	return max + 1
+}
+
+// apiShareDeleteHandler 處理 DELETE /api/shares/{hash}。
+func apiShareDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "DELETE" {
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.TrimPrefix(r.URL.Path, "/api/shares/")
+	if hash == "" {
+		http.Error(w, `{"error": "Missing share hash"}`, http.StatusBadRequest)
+		return
+	}
+
+	id, err := hashid.Decode(hash, hashid.ShareID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid share hash"}`, http.StatusNotFound)
+		return
+	}
+
+	if err := storage.RemoveShare(id); err != nil {
+		if errors.Is(err, ErrShareNotFound) {
+			http.Error(w, `{"error": "Share not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": "刪除分享連結失敗: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "分享連結已刪除"}`))
+}
+
+// publicShareHandler 處理 GET /s/{hash}：透過 storage.ConsumeShare 驗證分享連結是否
+// 仍然可用、視需要要求密碼並遞減剩餘觀看次數，最後回傳建立當下凍結的任務快照。
+//
+// 這是一個純資料端點，不會渲染唯讀的甘特圖頁面；前端 SPA 負責偵測 /s/{hash}
+// 路徑並以 fetch 呼叫這支 API 取得凍結的任務 JSON 後在畫面上渲染唯讀檢視。
+func publicShareHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, `{"error": "不支援的方法"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.TrimPrefix(r.URL.Path, "/s/")
+	id, err := hashid.Decode(hash, hashid.ShareID)
+	if err != nil {
+		http.Error(w, `{"error": "Share not found"}`, http.StatusNotFound)
+		return
+	}
+
+	share, err := storage.ConsumeShare(id, r.URL.Query().Get("password"))
+	switch {
+	case errors.Is(err, ErrShareNotFound):
+		http.Error(w, `{"error": "Share not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, ErrShareUnavailable):
+		http.Error(w, `{"error": "Share has expired or is no longer available"}`, http.StatusGone)
+		return
+	case errors.Is(err, ErrShareWrongPassword):
+		http.Error(w, `{"error": "Password required"}`, http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf(`{"error": "讀取分享連結失敗: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(share.TasksJSON)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Andrew12082005/GanttDiagram/hashid"
+)
+
+// getTestShare 準備一筆用於測試的分享連結。
+func getTestShare() Share {
+	remainViews := 3
+	expireAt := time.Now().Add(time.Hour)
+	return Share{
+		ID:          201,
+		Hash:        "testhash",
+		CreatedAt:   time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		ExpireAt:    &expireAt,
+		RemainViews: &remainViews,
+		Password:    "",
+		TasksJSON:   []byte(`[]`),
+	}
+}
+
+// shareColumns 是 shares 資料表固定的欄位順序，供多個測試重複使用。
+var shareColumns = []string{"id", "hash", "created_at", "expire_at", "remain_views", "password", "tasks_json"}
+
+func TestSaveSharesToDB(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM shares").WillReturnResult(sqlmock.NewResult(0, 0))
+	prep := mock.ExpectPrepare("INSERT INTO shares \\(id, hash, created_at, expire_at, remain_views, password, tasks_json\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\)")
+	prep.ExpectExec().
+		WithArgs(share.ID, share.Hash, share.CreatedAt, share.ExpireAt, share.RemainViews, share.Password, share.TasksJSON).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = saveSharesToDB([]Share{share})
+
+	assert.NoError(t, err, "saveSharesToDB 不應返回錯誤")
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+func TestConsumeShareFromDB_Success(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+	rows := sqlmock.NewRows(shareColumns).
+		AddRow(share.ID, share.Hash, share.CreatedAt, *share.ExpireAt, *share.RemainViews, share.Password, share.TasksJSON)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares WHERE id = \\$1").
+		WithArgs(share.ID).
+		WillReturnRows(rows)
+	mock.ExpectExec("UPDATE shares SET remain_views = \\$1 WHERE id = \\$2").
+		WithArgs(*share.RemainViews-1, share.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	got, err := consumeShareFromDB(share.ID, "")
+
+	assert.NoError(t, err, "consumeShareFromDB 不應返回錯誤")
+	assert.Equal(t, share.TasksJSON, got.TasksJSON)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+func TestConsumeShareFromDB_Expired(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+	expiredAt := time.Now().Add(-time.Hour)
+	rows := sqlmock.NewRows(shareColumns).
+		AddRow(share.ID, share.Hash, share.CreatedAt, expiredAt, *share.RemainViews, share.Password, share.TasksJSON)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares WHERE id = \\$1").
+		WithArgs(share.ID).
+		WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	_, err = consumeShareFromDB(share.ID, "")
+
+	assert.ErrorIs(t, err, ErrShareUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+func TestConsumeShareFromDB_ExhaustedViews(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+	rows := sqlmock.NewRows(shareColumns).
+		AddRow(share.ID, share.Hash, share.CreatedAt, *share.ExpireAt, 0, share.Password, share.TasksJSON)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares WHERE id = \\$1").
+		WithArgs(share.ID).
+		WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	_, err = consumeShareFromDB(share.ID, "")
+
+	assert.ErrorIs(t, err, ErrShareUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+func TestConsumeShareFromDB_WrongPassword(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+	share.Password = "secret"
+	rows := sqlmock.NewRows(shareColumns).
+		AddRow(share.ID, share.Hash, share.CreatedAt, *share.ExpireAt, *share.RemainViews, share.Password, share.TasksJSON)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, hash, created_at, expire_at, remain_views, password, tasks_json FROM shares WHERE id = \\$1").
+		WithArgs(share.ID).
+		WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	_, err = consumeShareFromDB(share.ID, "wrong-password")
+
+	assert.ErrorIs(t, err, ErrShareWrongPassword)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+// TestPersistNewShareToDB 確認 persistNewShareToDB 會在交易中分配 ID 並計算 Hash。
+func TestPersistNewShareToDB(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("創建 mock 資料庫時發生錯誤: %v", err)
+	}
+	defer mockDB.Close()
+	db = mockDB
+
+	share := getTestShare()
+	share.ID = 0
+	share.Hash = ""
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM shares").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(200))
+	mock.ExpectExec("INSERT INTO shares").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := persistNewShareToDB(share)
+
+	assert.NoError(t, err, "persistNewShareToDB 不應返回錯誤")
+	assert.Equal(t, 201, got.ID)
+	assert.Equal(t, hashid.Encode(201, hashid.ShareID), got.Hash)
+	assert.NoError(t, mock.ExpectationsWereMet(), "未滿足的 Mock 期望")
+}
+
+// TestPersistNewShareToFileConcurrent 確認 persistNewShareToFile 在並發呼叫下不會
+// 分配出重複的 ID，也不會遺失任何一次建立。
+func TestPersistNewShareToFileConcurrent(t *testing.T) {
+	oldWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWD)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := persistNewShareToFile(Share{TasksJSON: []byte(`[]`)}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	shares, err := loadSharesFromFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != n {
+		t.Fatalf("lost updates: created %d concurrently, only %d persisted", n, len(shares))
+	}
+
+	seen := make(map[int]bool, n)
+	for _, s := range shares {
+		if seen[s.ID] {
+			t.Fatalf("duplicate share ID %d", s.ID)
+		}
+		seen[s.ID] = true
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Andrew12082005/GanttDiagram/job"
+)
+
+// Storage 定義任務與分享連結的存取介面，讓 main 可以在 JSON 檔案與 SQL 資料庫之間
+// 切換，而不需要更動任何 HTTP 處理函數。
+type Storage interface {
+	Load() ([]Task, error)
+	Save(tasks []Task) error
+
+	LoadShares() ([]Share, error)
+	SaveShares(shares []Share) error
+	// PersistShare 原子性地分配 ID、計算雜湊 Hash 並建立一筆新的分享連結，
+	// JSON 後端以 dataMutex 保護，SQL 後端以交易保護，避免並發建立互相覆蓋
+	// 彼此的寫入。回傳的 Share 帶有分配到的 ID 與 Hash。
+	PersistShare(s Share) (Share, error)
+	// RemoveShare 原子性地移除指定 ID 的分享連結；找不到時回傳 ErrShareNotFound。
+	RemoveShare(id int) error
+
+	// ConsumeShare 原子性地讀取、驗證並（在有設定觀看次數上限時）遞減指定分享
+	// 連結的 RemainViews，JSON 後端以 dataMutex 保護，SQL 後端以交易保護。
+	ConsumeShare(id int, password string) (Share, error)
+
+	LoadJobs() ([]job.JobModel, error)
+	SaveJobs(records []job.JobModel) error
+	// PersistJob 原子性地更新（或新增）單一工作紀錄，JSON 後端以 dataMutex 保護，
+	// SQL 後端以交易保護，避免並發的狀態更新互相覆蓋彼此寫入的結果。
+	PersistJob(m job.JobModel) error
+	// PersistNewJob 原子性地分配 ID 並建立一筆新的工作紀錄，JSON 後端以
+	// dataMutex 保護，SQL 後端以交易保護，避免並發建立時算出相同的 ID。
+	// 回傳的 JobModel 帶有分配到的 ID。
+	PersistNewJob(m job.JobModel) (job.JobModel, error)
+
+	ListSettings() ([]Setting, error)
+	SaveSetting(s Setting) error
+	// GetSettingsByNames 查詢指定名稱的設定值，只回傳存在的項目；
+	// names 為空時必須直接回傳空結果，不可以對後端發出查詢。
+	GetSettingsByNames(names []string) (map[string]string, error)
+}
+
+// JSONStorage 是原本的檔案系統實作，資料儲存在 jsonFilePath 指向的檔案中。
+type JSONStorage struct{}
+
+// Load 從 jsonFilePath 讀取任務列表。
+func (JSONStorage) Load() ([]Task, error) {
+	return loadTasksFromFile()
+}
+
+// Save 將任務列表寫入 jsonFilePath。
+func (JSONStorage) Save(tasks []Task) error {
+	return saveTasksToFile(tasks)
+}
+
+// LoadShares 從 sharesFilePath 讀取分享連結列表。
+func (JSONStorage) LoadShares() ([]Share, error) {
+	return loadSharesFromFile()
+}
+
+// SaveShares 將分享連結列表寫入 sharesFilePath。
+func (JSONStorage) SaveShares(shares []Share) error {
+	return saveSharesToFile(shares)
+}
+
+// PersistShare 在 dataMutex 保護下分配 ID、計算 Hash 並建立一筆新的分享連結。
+func (JSONStorage) PersistShare(s Share) (Share, error) {
+	return persistNewShareToFile(s)
+}
+
+// RemoveShare 在 dataMutex 保護下移除指定 ID 的分享連結。
+func (JSONStorage) RemoveShare(id int) error {
+	return removeShareFromFile(id)
+}
+
+// ConsumeShare 在 dataMutex 保護下完成「讀取 → 驗證 → 遞減」，避免並發請求
+// 重複消耗同一個觀看名額。
+func (JSONStorage) ConsumeShare(id int, password string) (Share, error) {
+	dataMutex.Lock()
+	defer dataMutex.Unlock()
+
+	shares, err := loadSharesFromFileLocked()
+	if err != nil {
+		return Share{}, err
+	}
+
+	index := indexOfShare(shares, id)
+	if index == -1 {
+		return Share{}, ErrShareNotFound
+	}
+
+	share := shares[index]
+	if !share.IsAvailable() {
+		return Share{}, ErrShareUnavailable
+	}
+	if share.Password != "" && share.Password != password {
+		return Share{}, ErrShareWrongPassword
+	}
+
+	if share.RemainViews != nil {
+		remaining := *share.RemainViews - 1
+		shares[index].RemainViews = &remaining
+		if err := saveSharesToFileLocked(shares); err != nil {
+			return Share{}, err
+		}
+	}
+
+	return share, nil
+}
+
+// LoadJobs 從 jobsFilePath 讀取工作紀錄列表。
+func (JSONStorage) LoadJobs() ([]job.JobModel, error) {
+	return loadJobsFromFile()
+}
+
+// SaveJobs 將工作紀錄列表寫入 jobsFilePath。
+func (JSONStorage) SaveJobs(records []job.JobModel) error {
+	return saveJobsToFile(records)
+}
+
+// PersistJob 在 dataMutex 保護下更新（或新增）單一工作紀錄。
+func (JSONStorage) PersistJob(m job.JobModel) error {
+	return persistJobToFile(m)
+}
+
+// PersistNewJob 在 dataMutex 保護下分配 ID 並建立一筆新的工作紀錄。
+func (JSONStorage) PersistNewJob(m job.JobModel) (job.JobModel, error) {
+	return persistNewJobToFile(m)
+}
+
+// ListSettings 從 settingsFilePath 讀取所有設定。
+func (JSONStorage) ListSettings() ([]Setting, error) {
+	return loadSettingsFromFile()
+}
+
+// SaveSetting 新增或更新一筆設定。
+func (JSONStorage) SaveSetting(s Setting) error {
+	settings, err := loadSettingsFromFile()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, existing := range settings {
+		if existing.Name == s.Name {
+			settings[i] = s
+			found = true
+			break
+		}
+	}
+	if !found {
+		settings = append(settings, s)
+	}
+
+	return saveSettingsToFile(settings)
+}
+
+// GetSettingsByNames 讀取檔案並過濾出 names 要求的設定值；names 為空時直接回傳空結果。
+func (JSONStorage) GetSettingsByNames(names []string) (map[string]string, error) {
+	result := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	settings, err := loadSettingsFromFile()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+	for _, s := range settings {
+		if wanted[s.Name] {
+			result[s.Name] = s.Value
+		}
+	}
+	return result, nil
+}
+
+// db 是目前使用中的資料庫連線，由 main 依 -storage 與 -dsn 參數初始化；
+// 測試檔案會直接覆寫這個變數為 sqlmock 實例。
+var db *sql.DB
+
+// SQLStorage 透過 database/sql 將任務存放在 tasks 資料表中，
+// 底層驅動 (sqlite3 或 postgres) 由 -dsn 決定。
+type SQLStorage struct{}
+
+// Load 從 tasks 資料表讀取所有任務。
+func (SQLStorage) Load() ([]Task, error) {
+	return loadTasksFromDB()
+}
+
+// Save 在單一交易中清空 tasks 資料表並寫入新的任務列表。
+func (SQLStorage) Save(tasks []Task) error {
+	return saveTasksToDB(tasks)
+}
+
+// LoadShares 從 shares 資料表讀取所有分享連結。
+func (SQLStorage) LoadShares() ([]Share, error) {
+	return loadSharesFromDB()
+}
+
+// SaveShares 在單一交易中清空 shares 資料表並寫入新的分享連結列表。
+func (SQLStorage) SaveShares(shares []Share) error {
+	return saveSharesToDB(shares)
+}
+
+// PersistShare 在單一交易中分配 ID、計算 Hash 並建立一筆新的分享連結。
+func (SQLStorage) PersistShare(s Share) (Share, error) {
+	return persistNewShareToDB(s)
+}
+
+// RemoveShare 在單一交易中移除指定 ID 的分享連結。
+func (SQLStorage) RemoveShare(id int) error {
+	return removeShareFromDB(id)
+}
+
+// ConsumeShare 在單一交易中完成「讀取 → 驗證 → 遞減」，交易的隔離性取代了
+// JSONStorage 需要的 dataMutex。
+func (SQLStorage) ConsumeShare(id int, password string) (Share, error) {
+	return consumeShareFromDB(id, password)
+}
+
+// LoadJobs 從 jobs 資料表讀取所有工作紀錄。
+func (SQLStorage) LoadJobs() ([]job.JobModel, error) {
+	return loadJobsFromDB()
+}
+
+// SaveJobs 在單一交易中清空 jobs 資料表並寫入新的工作紀錄列表。
+func (SQLStorage) SaveJobs(records []job.JobModel) error {
+	return saveJobsToDB(records)
+}
+
+// PersistJob 在單一交易中 upsert 單一工作紀錄。
+func (SQLStorage) PersistJob(m job.JobModel) error {
+	return persistJobToDB(m)
+}
+
+// PersistNewJob 在單一交易中分配 ID 並建立一筆新的工作紀錄。
+func (SQLStorage) PersistNewJob(m job.JobModel) (job.JobModel, error) {
+	return persistNewJobToDB(m)
+}
+
+// ListSettings 從 settings 資料表讀取所有設定。
+func (SQLStorage) ListSettings() ([]Setting, error) {
+	return listSettingsFromDB()
+}
+
+// SaveSetting 以 UPSERT 的方式新增或更新一筆設定。
+func (SQLStorage) SaveSetting(s Setting) error {
+	return saveSettingToDB(s)
+}
+
+// GetSettingsByNames 只在 names 非空時，對 settings 資料表發出一次
+// `WHERE name IN (...)` 查詢；names 為空時直接回傳空結果，避免發出無意義的查詢。
+func (SQLStorage) GetSettingsByNames(names []string) (map[string]string, error) {
+	return getSettingsByNamesFromDB(names)
+}
+
+// loadTasksFromDB 執行 SELECT 查詢，將 tasks 資料表的內容轉換為 Task 列表。
+func loadTasksFromDB() ([]Task, error) {
+	rows, err := db.Query("SELECT id, name, start, durationDays, color, priority FROM tasks")
+	if err != nil {
+		return nil, fmt.Errorf("查詢任務資料失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.ID, &t.Name, &t.Start, &t.DurationDays, &t.Color, &t.Priority); err != nil {
+			return nil, fmt.Errorf("解析任務資料失敗: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取任務資料失敗: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// saveTasksToDB 在交易中清空 tasks 資料表，接著逐筆寫入新的任務列表；
+// 任何一步失敗都會回滾整個交易。
+func saveTasksToDB(tasks []Task) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("開啟交易失敗: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空任務資料表失敗: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO tasks (id, name, start, durationDays, color, priority) VALUES ($1, $2, $3, $4, $5, $6)")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("準備寫入語句失敗: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range tasks {
+		if _, err := stmt.Exec(t.ID, t.Name, t.Start, t.DurationDays, t.Color, t.Priority); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("寫入任務 %d 失敗: %w", t.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易失敗: %w", err)
+	}
+
+	return nil
+}
+
+// newStorage 依 storageKind 建立對應的 Storage 實作；sqlite/postgres 會先以 dsn 開啟資料庫連線。
+func newStorage(storageKind, dsn string) (Storage, error) {
+	switch storageKind {
+	case "json":
+		return JSONStorage{}, nil
+	case "sqlite", "postgres":
+		driver := "sqlite3"
+		if storageKind == "postgres" {
+			driver = "postgres"
+		}
+		conn, err := sql.Open(driver, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("開啟資料庫連線失敗: %w", err)
+		}
+		db = conn
+		if err := ensureSchema(driver); err != nil {
+			return nil, err
+		}
+		return SQLStorage{}, nil
+	default:
+		return nil, fmt.Errorf("不支援的 storage 類型: %s", storageKind)
+	}
+}
+
+// ensureSchema 在 sqlite/postgres 後端上建立 tasks、shares、jobs、settings 資料表
+// （如果尚不存在），讓 -storage=sqlite 或 -storage=postgres 可以直接對一個全新的
+// 資料庫運作，不需要操作者手動建表。
+func ensureSchema(driver string) error {
+	blobType := "BLOB"
+	if driver == "postgres" {
+		blobType = "BYTEA"
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			start TEXT NOT NULL,
+			durationDays INTEGER NOT NULL,
+			color TEXT NOT NULL,
+			priority INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS shares (
+			id INTEGER PRIMARY KEY,
+			hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expire_at TIMESTAMP,
+			remain_views INTEGER,
+			password TEXT NOT NULL,
+			tasks_json ` + blobType + ` NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS jobs (
+			id INTEGER PRIMARY KEY,
+			type TEXT NOT NULL,
+			props TEXT NOT NULL,
+			status INTEGER NOT NULL,
+			error TEXT NOT NULL,
+			result ` + blobType + `,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS settings (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			type TEXT NOT NULL
+		)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("建立資料表失敗: %w", err)
+		}
+	}
+	return nil
+}